@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+)
+
+type extractionConfig struct {
+	Enabled      bool          `env:"EXTRACT_CONTENT" envDefault:"false"`
+	UserAgent    string        `env:"EXTRACT_USER_AGENT" envDefault:"pocket-to-joplin/1.0"`
+	Timeout      time.Duration `env:"EXTRACT_TIMEOUT" envDefault:"15s"`
+	InlineImages bool          `env:"INLINE_IMAGES" envDefault:"true"`
+	MaxBodySize  int64         `env:"EXTRACT_MAX_BODY_SIZE" envDefault:"5242880"`
+}
+
+// contentSelectors are tried in order against the downloaded page; the first
+// one that matches anything is treated as the article's main content, which
+// is a much cheaper approximation of readability-style extraction than a
+// full scoring algorithm but works for the vast majority of article pages.
+var contentSelectors = []string{
+	"article",
+	"[role=main]",
+	"main",
+	"#content",
+	".post-content",
+	".article-content",
+}
+
+// extractArticle downloads articleURL, isolates its main content and
+// converts it to Markdown. If extractCfg.InlineImages is set, images found
+// in the content are downloaded and uploaded to Joplin as resources, with
+// the Markdown rewritten to point at them via the `:/<resource_id>` scheme.
+func extractArticle(ctx context.Context, extractCfg extractionConfig, joplin *joplinClient, articleURL string) (string, error) {
+	client := &http.Client{Timeout: extractCfg.Timeout}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, articleURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", extractCfg.UserAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download article, status code: %d", resp.StatusCode)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(io.LimitReader(resp.Body, extractCfg.MaxBodySize))
+	if err != nil {
+		return "", err
+	}
+
+	content := mainContent(doc)
+
+	if extractCfg.InlineImages {
+		if err := inlineImages(ctx, content, client, extractCfg.UserAgent, articleURL, joplin); err != nil {
+			return "", fmt.Errorf("failed to inline images: %w", err)
+		}
+	}
+
+	htmlStr, err := content.Html()
+	if err != nil {
+		return "", err
+	}
+
+	converter := md.NewConverter(domainFromURL(articleURL), true, nil)
+	markdown, err := converter.ConvertString(htmlStr)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(markdown), nil
+}
+
+// mainContent returns the selection most likely to hold the article's body,
+// falling back to the whole document body if none of contentSelectors match.
+func mainContent(doc *goquery.Document) *goquery.Selection {
+	for _, selector := range contentSelectors {
+		if sel := doc.Find(selector).First(); sel.Length() > 0 {
+			return sel
+		}
+	}
+	return doc.Find("body")
+}
+
+// inlineImages downloads every <img> under content, uploads it to Joplin as
+// a resource, and rewrites the element's src to the `:/<resource_id>`
+// reference Joplin expects in note bodies. A single image failing to
+// download (dead links, hotlink protection, etc. are common) doesn't fail
+// the whole extraction; that image is just removed and logged.
+func inlineImages(ctx context.Context, content *goquery.Selection, client *http.Client, userAgent, pageURL string, joplin *joplinClient) error {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return err
+	}
+
+	content.Find("img").Each(func(_ int, img *goquery.Selection) {
+		src, ok := img.Attr("src")
+		if !ok || src == "" {
+			return
+		}
+
+		imgURL, err := base.Parse(src)
+		if err != nil {
+			return
+		}
+
+		resourceID, err := downloadAndUploadImage(ctx, client, userAgent, imgURL.String(), joplin)
+		if err != nil {
+			fmt.Println("Error inlining image, skipping it:", err)
+			img.Remove()
+			return
+		}
+
+		img.SetAttr("src", ":/"+resourceID)
+	})
+
+	return nil
+}
+
+func downloadAndUploadImage(ctx context.Context, client *http.Client, userAgent, imgURL string, joplin *joplinClient) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, imgURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download image, status code: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	filename := path.Base(imgURL)
+	if filename == "" || filename == "." || filename == "/" {
+		filename = "image"
+	}
+
+	return joplin.createResource(ctx, filename, data)
+}
+
+// noteBodyForArticle builds the Joplin note body for article: the extracted
+// content if any, falling back to Pocket's excerpt, followed by a short
+// footer recording when the article was saved to Pocket.
+func noteBodyForArticle(article PocketArticle, extracted string) string {
+	body := extracted
+	if body == "" {
+		body = fallbackBody(article)
+	}
+
+	if footer := savedDateFooter(article); footer != "" {
+		body += footer
+	}
+
+	return body
+}
+
+// fallbackBody is used when content extraction is disabled or fails: it
+// leads with Pocket's excerpt, if any, followed by a link back to the
+// original article.
+func fallbackBody(article PocketArticle) string {
+	if article.Excerpt == "" {
+		return article.URL
+	}
+	return fmt.Sprintf("%s\n\n[Read the original article](%s)", article.Excerpt, article.URL)
+}
+
+// savedDateFooter formats article.TimeAdded as a short note-body footer, or
+// "" if the timestamp can't be parsed.
+func savedDateFooter(article PocketArticle) string {
+	ts := parseUnixTimestamp(article.TimeAdded)
+	if ts == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n\n---\nSaved to Pocket on %s", time.Unix(ts, 0).UTC().Format("2006-01-02"))
+}
+
+func domainFromURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return parsed.Scheme + "://" + parsed.Host
+}
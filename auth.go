@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/caarlos0/env/v11"
+)
+
+const (
+	pocketOAuthRequestURL   = "https://getpocket.com/v3/oauth/request"
+	pocketOAuthAuthorizeURL = "https://getpocket.com/v3/oauth/authorize"
+	pocketAuthPageURL       = "https://getpocket.com/auth/authorize"
+
+	authCallbackTimeout = 5 * time.Minute
+)
+
+type authConfig struct {
+	ConsumerKey  string `env:"POCKET_CONSUMER_KEY,required"`
+	CallbackPort int    `env:"POCKET_AUTH_PORT" envDefault:"53142"`
+}
+
+// persistedConfig is the on-disk shape of ~/.config/pocket-to-joplin/config.toml,
+// used to avoid making users keep POCKET_ACCESS_TOKEN in the environment once
+// they've authorized once.
+type persistedConfig struct {
+	Pocket struct {
+		AccessToken string `toml:"access_token"`
+	} `toml:"pocket"`
+}
+
+type pocketOAuthRequestResponse struct {
+	Code string `json:"code"`
+}
+
+type pocketOAuthAuthorizeResponse struct {
+	AccessToken string `json:"access_token"`
+	Username    string `json:"username"`
+}
+
+// runAuthCommand drives the full Pocket OAuth dance: it obtains a request
+// token, sends the user to Pocket to authorize it, waits for Pocket to
+// redirect back to a local server once that's done, then exchanges the
+// request token for a long-lived access token and persists it to disk.
+func runAuthCommand() error {
+	cfg := authConfig{}
+	if err := env.Parse(&cfg); err != nil {
+		return fmt.Errorf("failed to parse auth config: %w", err)
+	}
+
+	redirectURI := fmt.Sprintf("http://127.0.0.1:%d/callback", cfg.CallbackPort)
+
+	requestToken, err := requestPocketToken(cfg.ConsumerKey, redirectURI)
+	if err != nil {
+		return fmt.Errorf("failed to obtain a Pocket request token: %w", err)
+	}
+
+	callbackReceived := make(chan struct{})
+	server := &http.Server{Addr: fmt.Sprintf("127.0.0.1:%d", cfg.CallbackPort)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "Pocket authorization complete, you can close this tab and return to the terminal.")
+		close(callbackReceived)
+	})
+	server.Handler = mux
+
+	serverErr := make(chan error, 1)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			serverErr <- err
+		}
+	}()
+
+	authorizeURL := fmt.Sprintf("%s?request_token=%s&redirect_uri=%s",
+		pocketAuthPageURL,
+		url.QueryEscape(requestToken),
+		url.QueryEscape(redirectURI),
+	)
+
+	fmt.Println("Opening your browser to authorize pocket-to-joplin with Pocket...")
+	fmt.Println(authorizeURL)
+	if err := openBrowser(authorizeURL); err != nil {
+		fmt.Println("Could not open a browser automatically, please open the URL above manually.")
+	}
+
+	select {
+	case <-callbackReceived:
+	case err := <-serverErr:
+		return fmt.Errorf("callback server failed: %w", err)
+	case <-time.After(authCallbackTimeout):
+		_ = server.Close()
+		return fmt.Errorf("timed out waiting for Pocket authorization callback")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_ = server.Shutdown(ctx)
+
+	accessToken, username, err := authorizePocketToken(cfg.ConsumerKey, requestToken)
+	if err != nil {
+		return fmt.Errorf("failed to exchange the request token for an access token: %w", err)
+	}
+
+	if err := savePersistedAccessToken(accessToken); err != nil {
+		return fmt.Errorf("failed to persist the Pocket access token: %w", err)
+	}
+
+	path, err := configFilePath()
+	if err == nil {
+		fmt.Printf("Authorized as %q, access token saved to %s\n", username, path)
+	} else {
+		fmt.Printf("Authorized as %q\n", username)
+	}
+
+	return nil
+}
+
+func requestPocketToken(consumerKey, redirectURI string) (string, error) {
+	payload := map[string]string{
+		"consumer_key": consumerKey,
+		"redirect_uri": redirectURI,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, pocketOAuthRequestURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.Header.Set("X-Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to request a Pocket request token, status code: %d", resp.StatusCode)
+	}
+
+	var requestResp pocketOAuthRequestResponse
+	if err := json.NewDecoder(resp.Body).Decode(&requestResp); err != nil {
+		return "", err
+	}
+
+	return requestResp.Code, nil
+}
+
+func authorizePocketToken(consumerKey, requestToken string) (accessToken, username string, err error) {
+	payload := map[string]string{
+		"consumer_key": consumerKey,
+		"code":         requestToken,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", "", err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, pocketOAuthAuthorizeURL, bytes.NewReader(body))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.Header.Set("X-Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("failed to authorize the Pocket request token, status code: %d", resp.StatusCode)
+	}
+
+	var authorizeResp pocketOAuthAuthorizeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&authorizeResp); err != nil {
+		return "", "", err
+	}
+
+	return authorizeResp.AccessToken, authorizeResp.Username, nil
+}
+
+// openBrowser opens url in the user's default browser, on a best-effort basis.
+func openBrowser(url string) error {
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", url).Start()
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url).Start()
+	default:
+		return exec.Command("xdg-open", url).Start()
+	}
+}
+
+func configFilePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "pocket-to-joplin", "config.toml"), nil
+}
+
+// loadPersistedAccessToken reads the Pocket access token saved by a previous
+// "auth" run, returning an empty string if no config file exists yet.
+func loadPersistedAccessToken() (string, error) {
+	path, err := configFilePath()
+	if err != nil {
+		return "", err
+	}
+
+	var cfg persistedConfig
+	if _, err := toml.DecodeFile(path, &cfg); err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return cfg.Pocket.AccessToken, nil
+}
+
+func savePersistedAccessToken(token string) error {
+	path, err := configFilePath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	var cfg persistedConfig
+	cfg.Pocket.AccessToken = token
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return toml.NewEncoder(f).Encode(cfg)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	maxRequestRetries = 4
+	baseRetryBackoff  = 500 * time.Millisecond
+	maxRetryBackoff   = 8 * time.Second
+)
+
+// retryingClient wraps an *http.Client with a per-attempt deadline, a token
+// bucket rate limit, and exponential backoff with jitter on 5xx responses
+// and transport errors. It's shared by pocketClient and joplinClient.
+type retryingClient struct {
+	httpClient *http.Client
+	limiter    *rate.Limiter
+	timeout    time.Duration
+}
+
+func newRetryingClient(httpClient *http.Client, limiter *rate.Limiter, timeout time.Duration) *retryingClient {
+	return &retryingClient{httpClient: httpClient, limiter: limiter, timeout: timeout}
+}
+
+// do executes the request built by newReq, retrying on 5xx responses and
+// transport errors. newReq is called again on every attempt, since a
+// request's body can only be read once, and receives a context scoped to
+// that attempt's own deadline, so one slow attempt timing out doesn't affect
+// the deadline available to the next. The returned response's body cancels
+// that context when closed, rather than do canceling it up front, so the
+// caller can still read the body after do returns.
+func (c *retryingClient) do(ctx context.Context, newReq func(ctx context.Context) (*http.Request, error)) (*http.Response, error) {
+	var lastErr error
+	for attempt := 0; attempt <= maxRequestRetries; attempt++ {
+		if c.limiter != nil {
+			if err := c.limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		attemptCtx, cancel := context.WithTimeout(ctx, c.timeout)
+
+		req, err := newReq(attemptCtx)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+
+		resp, err := c.httpClient.Do(req)
+		if err == nil && resp.StatusCode < http.StatusInternalServerError {
+			resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+			return resp, nil
+		}
+
+		if err == nil {
+			lastErr = fmt.Errorf("request failed, status code: %d", resp.StatusCode)
+			resp.Body.Close()
+		} else {
+			lastErr = err
+		}
+		cancel()
+
+		if attempt == maxRequestRetries {
+			break
+		}
+
+		select {
+		case <-time.After(retryBackoff(attempt)):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	return nil, lastErr
+}
+
+// cancelOnCloseBody defers canceling an attempt's context until the caller
+// is done reading its response body, since the context also governs the
+// body's underlying connection.
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// retryBackoff returns an exponential backoff for the given attempt number
+// (0-indexed) with +/-50% jitter, capped at maxRetryBackoff.
+func retryBackoff(attempt int) time.Duration {
+	backoff := time.Duration(float64(baseRetryBackoff) * math.Pow(2, float64(attempt)))
+	if backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
+	}
+	jitter := time.Duration(rand.Int63n(int64(backoff))) - backoff/2
+	return backoff + jitter
+}
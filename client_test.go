@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryBackoffCapsAtMax(t *testing.T) {
+	for attempt := 0; attempt < 10; attempt++ {
+		backoff := retryBackoff(attempt)
+		if backoff <= 0 {
+			t.Fatalf("retryBackoff(%d) = %v, want > 0", attempt, backoff)
+		}
+		// retryBackoff jitters by up to +50% on top of the capped backoff.
+		if max := maxRetryBackoff + maxRetryBackoff/2; backoff > max {
+			t.Fatalf("retryBackoff(%d) = %v, want <= %v", attempt, backoff, max)
+		}
+	}
+}
+
+func TestRetryingClientDoRetriesAfterAttemptTimeout(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&attempts, 1)
+		if n == 1 {
+			// Exceed the client's per-attempt timeout so the first attempt's
+			// context is canceled before it gets a response.
+			time.Sleep(50 * time.Millisecond)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := newRetryingClient(&http.Client{}, nil, 10*time.Millisecond)
+
+	resp, err := client.do(context.Background(), func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	})
+	if err != nil {
+		t.Fatalf("do returned an error, the client should have retried: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status code = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	if got := atomic.LoadInt32(&attempts); got < 2 {
+		t.Errorf("server saw %d attempt(s), want at least 2 (no retry happened after the timeout)", got)
+	}
+}
+
+func TestRetryingClientDoReadsBodyAfterReturn(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	client := newRetryingClient(&http.Client{}, nil, time.Second)
+
+	resp, err := client.do(context.Background(), func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	})
+	if err != nil {
+		t.Fatalf("do returned an error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading the response body failed: %v", err)
+	}
+	if string(body) != "hello" {
+		t.Errorf("body = %q, want %q", body, "hello")
+	}
+}
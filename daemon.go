@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type runConfig struct {
+	RunMode      string        `env:"RUN_MODE" envDefault:"oneshot"`
+	SyncInterval time.Duration `env:"SYNC_INTERVAL" envDefault:"15m"`
+	HealthAddr   string        `env:"HEALTH_ADDR" envDefault:":9090"`
+}
+
+var (
+	healthMu     sync.RWMutex
+	healthy      = true
+	lastSyncTime time.Time
+)
+
+func setSyncHealth(ok bool, at time.Time) {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	healthy = ok
+	if !at.IsZero() {
+		lastSyncTime = at
+	}
+}
+
+// runDaemon runs the sync on a SYNC_INTERVAL ticker until SIGINT/SIGTERM is
+// received. The signal is delivered on a buffered channel that's only
+// checked between syncs, so a signal arriving mid-sync is queued and the
+// in-flight sync always runs to completion before the process exits -- the
+// same shape as a signal handler that just flips a flag and lets the
+// current unit of work finish instead of tearing it down mid-flight.
+func runDaemon(cfg config) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigCh)
+
+	stopHealthServer := startHealthServer(cfg.Run.HealthAddr)
+	defer stopHealthServer()
+
+	ticker := time.NewTicker(cfg.Run.SyncInterval)
+	defer ticker.Stop()
+
+	fmt.Printf("Running in daemon mode, syncing every %s\n", cfg.Run.SyncInterval)
+
+	for {
+		setSyncHealth(false, time.Time{})
+		if err := runSync(context.Background(), cfg, false); err != nil {
+			fmt.Println("Error during scheduled sync:", err)
+		}
+		setSyncHealth(true, time.Now())
+
+		select {
+		case sig := <-sigCh:
+			fmt.Printf("Received %s, shutting down\n", sig)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// startHealthServer exposes /healthz and /metrics so the daemon process can
+// be supervised, and returns a function that shuts it down.
+func startHealthServer(addr string) func() {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		healthMu.RLock()
+		ok := healthy
+		healthMu.RUnlock()
+
+		if !ok {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprintln(w, "syncing")
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		healthMu.RLock()
+		defer healthMu.RUnlock()
+
+		healthyValue := 0
+		if healthy {
+			healthyValue = 1
+		}
+		fmt.Fprintf(w, "pocket_to_joplin_healthy %d\n", healthyValue)
+		fmt.Fprintf(w, "pocket_to_joplin_last_sync_unixtime %d\n", lastSyncTime.Unix())
+	})
+
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fmt.Println("Error serving health endpoint:", err)
+		}
+	}()
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}
+}
+
+// isTerminal reports whether f is attached to an interactive terminal,
+// used to suppress the progress bar when stdout is redirected.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return (info.Mode() & os.ModeCharDevice) != 0
+}
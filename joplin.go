@@ -0,0 +1,373 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type JoplinTag struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+type JoplinFolder struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// joplinClient wraps the local Joplin Web Clipper API behind a retrying
+// HTTP client. Joplin is a local, unrate-limited service, so no token
+// bucket is applied here, only the shared timeout/retry behavior.
+type joplinClient struct {
+	config joplinConfig
+	client *retryingClient
+}
+
+func newJoplinClient(config joplinConfig) *joplinClient {
+	return &joplinClient{
+		config: config,
+		client: newRetryingClient(&http.Client{}, nil, 30*time.Second),
+	}
+}
+
+// authedRequest builds a request against a Joplin endpoint, authenticating
+// both via the `token` query parameter (required by every endpoint) and an
+// Authorization header (honored by the newer note/resource endpoints).
+func (j *joplinClient) authedRequest(ctx context.Context, method, path string, body []byte) (*http.Request, error) {
+	sep := "?"
+	if strings.ContainsRune(path, '?') {
+		sep = "&"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, j.config.BaseURL+path+sep+"token="+j.config.Token, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+j.config.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return req, nil
+}
+
+func (j *joplinClient) getOrCreateToReadTag(ctx context.Context) (string, error) {
+	tags, err := j.fetchTags(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	for _, tag := range tags {
+		if tag.Title == "to_read" {
+			return tag.ID, nil
+		}
+	}
+
+	return j.createTag(ctx, "to_read")
+}
+
+func (j *joplinClient) fetchTags(ctx context.Context) ([]JoplinTag, error) {
+	resp, err := j.client.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return j.authedRequest(ctx, http.MethodGet, "/tags", nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch tags, status code: %d", resp.StatusCode)
+	}
+
+	var respStruct struct {
+		Tags []JoplinTag `json:"items"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&respStruct); err != nil {
+		return nil, err
+	}
+
+	return respStruct.Tags, nil
+}
+
+func (j *joplinClient) createTag(ctx context.Context, title string) (string, error) {
+	body, err := json.Marshal(JoplinTag{Title: title})
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := j.client.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return j.authedRequest(ctx, http.MethodPost, "/tags", body)
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to create tag, status code: %d", resp.StatusCode)
+	}
+
+	var createdTag JoplinTag
+	if err = json.NewDecoder(resp.Body).Decode(&createdTag); err != nil {
+		return "", err
+	}
+
+	return createdTag.ID, nil
+}
+
+func (j *joplinClient) getOrCreateMainFolder(ctx context.Context) (string, error) {
+	return j.getOrCreateFolder(ctx, "Main")
+}
+
+func (j *joplinClient) getOrCreateArchiveFolder(ctx context.Context) (string, error) {
+	return j.getOrCreateFolder(ctx, "Archived")
+}
+
+func (j *joplinClient) getOrCreateFolder(ctx context.Context, title string) (string, error) {
+	folders, err := j.fetchFolders(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	for _, folder := range folders {
+		if folder.Title == title {
+			return folder.ID, nil
+		}
+	}
+
+	return j.createFolder(ctx, title)
+}
+
+func (j *joplinClient) fetchFolders(ctx context.Context) ([]JoplinFolder, error) {
+	resp, err := j.client.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return j.authedRequest(ctx, http.MethodGet, "/folders", nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch folders, status code: %d", resp.StatusCode)
+	}
+
+	var respStruct struct {
+		Folders []JoplinFolder `json:"items"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&respStruct); err != nil {
+		return nil, err
+	}
+
+	return respStruct.Folders, nil
+}
+
+func (j *joplinClient) createFolder(ctx context.Context, title string) (string, error) {
+	body, err := json.Marshal(JoplinFolder{Title: title})
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := j.client.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return j.authedRequest(ctx, http.MethodPost, "/folders", body)
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to create folder, status code: %d", resp.StatusCode)
+	}
+
+	var createdFolder JoplinFolder
+	if err = json.NewDecoder(resp.Body).Decode(&createdFolder); err != nil {
+		return "", err
+	}
+
+	return createdFolder.ID, nil
+}
+
+func (j *joplinClient) createNoteForArticle(ctx context.Context, parentID string, article PocketArticle, body string) (string, error) {
+	note := map[string]string{
+		"title":     article.Title,
+		"body":      body,
+		"parent_id": parentID,
+	}
+	noteBody, err := json.Marshal(note)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := j.client.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return j.authedRequest(ctx, http.MethodPost, "/notes", noteBody)
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to create note, status code: %d", resp.StatusCode)
+	}
+
+	var createdNote struct {
+		ID string `json:"id"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&createdNote); err != nil {
+		return "", err
+	}
+
+	return createdNote.ID, nil
+}
+
+func (j *joplinClient) addNoteTag(ctx context.Context, tagID, noteID string) error {
+	body, err := json.Marshal(map[string]string{"id": noteID})
+	if err != nil {
+		return err
+	}
+
+	resp, err := j.client.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return j.authedRequest(ctx, http.MethodPost, "/tags/"+tagID+"/notes", body)
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to tag note, status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (j *joplinClient) updateNoteForArticle(ctx context.Context, noteID string, article PocketArticle, body string) error {
+	note := map[string]string{
+		"title": article.Title,
+		"body":  body,
+	}
+	noteBody, err := json.Marshal(note)
+	if err != nil {
+		return err
+	}
+
+	resp, err := j.client.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return j.authedRequest(ctx, http.MethodPut, "/notes/"+noteID, noteBody)
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to update note, status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// removeNoteForArticle deletes noteID, or moves it to the "Archived" folder
+// instead when archiveBehavior is "archive". archiveFolderID caches the
+// folder's id across calls so it's only looked up/created once per run.
+func (j *joplinClient) removeNoteForArticle(ctx context.Context, archiveBehavior, noteID string, archiveFolderID *string) error {
+	if archiveBehavior != "archive" {
+		return j.deleteNote(ctx, noteID)
+	}
+
+	if *archiveFolderID == "" {
+		folderID, err := j.getOrCreateArchiveFolder(ctx)
+		if err != nil {
+			return err
+		}
+		*archiveFolderID = folderID
+	}
+
+	return j.moveNote(ctx, noteID, *archiveFolderID)
+}
+
+func (j *joplinClient) deleteNote(ctx context.Context, noteID string) error {
+	resp, err := j.client.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return j.authedRequest(ctx, http.MethodDelete, "/notes/"+noteID, nil)
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to delete note, status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+func (j *joplinClient) moveNote(ctx context.Context, noteID, parentID string) error {
+	body, err := json.Marshal(map[string]string{"parent_id": parentID})
+	if err != nil {
+		return err
+	}
+
+	resp, err := j.client.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return j.authedRequest(ctx, http.MethodPut, "/notes/"+noteID, body)
+	})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to move note, status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// createResource uploads data to Joplin's /resources endpoint and returns
+// the new resource's id for use in a note body as `:/<resource_id>`.
+func (j *joplinClient) createResource(ctx context.Context, filename string, data []byte) (string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	part, err := writer.CreateFormFile("data", filename)
+	if err != nil {
+		return "", err
+	}
+	if _, err := part.Write(data); err != nil {
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	resp, err := j.client.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		req, err := j.authedRequest(ctx, http.MethodPost, "/resources", body.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		return req, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to create resource, status code: %d", resp.StatusCode)
+	}
+
+	var createdResource struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&createdResource); err != nil {
+		return "", err
+	}
+
+	return createdResource.ID, nil
+}
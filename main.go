@@ -1,17 +1,18 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
+	"os"
+	"strconv"
 
 	"github.com/caarlos0/env/v11"
+	"github.com/cheggaaa/pb/v3"
 )
 
 type pocketConfig struct {
 	ConsumerKey string `env:"POCKET_CONSUMER_KEY,required"`
-	AccessToken string `env:"POCKET_ACCESS_TOKEN,required"`
+	AccessToken string `env:"POCKET_ACCESS_TOKEN"`
 }
 
 type joplinConfig struct {
@@ -20,253 +21,201 @@ type joplinConfig struct {
 }
 
 type config struct {
-	pocketConfig pocketConfig
-	joplinConfig joplinConfig
-}
-
-type PocketArticle struct {
-	ItemID string `json:"item_id"`
-	Title  string `json:"resolved_title"`
-	URL    string `json:"resolved_url"`
-}
-
-type PocketResponse struct {
-	List map[string]PocketArticle `json:"list"`
-}
-
-type JoplinTag struct {
-	ID    string `json:"id"`
-	Title string `json:"title"`
-}
-
-type JoplinFolder struct {
-	ID    string `json:"id"`
-	Title string `json:"title"`
+	Pocket     pocketConfig
+	Joplin     joplinConfig
+	Extraction extractionConfig
+	State      stateConfig
+	TagSync    tagSyncConfig
+	Run        runConfig
 }
 
 func main() {
-	cfg := config{}
-	if err := env.Parse(&cfg); err != nil {
-		fmt.Printf("%+v\n", err)
-	}
-
-	articles, err := fetchUnreadArticles(cfg.pocketConfig)
-	if err != nil {
-		fmt.Println("Error fetching articles from Pocket:", err)
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "auth" {
+		if err := runAuthCommand(); err != nil {
+			fmt.Println("Error authorizing with Pocket:", err)
+			os.Exit(1)
+		}
 		return
 	}
 
-	tagID, err := getOrCreateToReadTag(cfg.joplinConfig)
-	if err != nil {
-		fmt.Println("Error getting or creating 'to_read' tag in Joplin:", err)
-		return
+	daemon := false
+	silent := false
+	for _, arg := range args {
+		switch arg {
+		case "--daemon":
+			daemon = true
+		case "--silent":
+			silent = true
+		}
 	}
 
-	folderID, err := getOrCreateMainFolder(cfg.joplinConfig)
-	if err != nil {
-		fmt.Println("Error getting or creating 'Main' folder in Joplin:", err)
-		return
+	cfg := config{}
+	if err := env.Parse(&cfg); err != nil {
+		fmt.Printf("%+v\n", err)
 	}
 
-	for _, article := range articles {
-		err = createJoplinNoteForArticle(tagID, folderID, cfg.joplinConfig, article)
+	if cfg.Pocket.AccessToken == "" {
+		token, err := loadPersistedAccessToken()
 		if err != nil {
-			fmt.Println("Error creating note in Joplin:", err)
+			fmt.Println("Error loading persisted Pocket access token:", err)
+			return
 		}
+		if token == "" {
+			fmt.Println("No Pocket access token found. Run \"pocket-to-joplin auth\" to authorize, or set POCKET_ACCESS_TOKEN.")
+			return
+		}
+		cfg.Pocket.AccessToken = token
 	}
 
-	fmt.Println("All articles have been processed.")
-}
-
-func fetchUnreadArticles(config pocketConfig) ([]PocketArticle, error) {
-	resp, err := http.Get(
-		fmt.Sprintf("https://getpocket.com/v3/get?consumer_key=%s&access_token=%s&state=unread&detailType=simple",
-			config.ConsumerKey,
-			config.AccessToken,
-		),
-	)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch articles, status code: %d", resp.StatusCode)
-	}
-
-	var pocketResp PocketResponse
-	if err = json.NewDecoder(resp.Body).Decode(&pocketResp); err != nil {
-		return nil, err
+	if daemon || cfg.Run.RunMode == "daemon" {
+		runDaemon(cfg)
+		return
 	}
 
-	articles := make([]PocketArticle, 0, len(pocketResp.List))
-	for _, article := range pocketResp.List {
-		articles = append(articles, article)
+	showProgress := !silent && isTerminal(os.Stdout)
+	if err := runSync(context.Background(), cfg, showProgress); err != nil {
+		fmt.Println("Error syncing Pocket to Joplin:", err)
 	}
-
-	return articles, nil
 }
 
-func getOrCreateToReadTag(config joplinConfig) (string, error) {
-	tags, err := fetchJoplinTags(config)
-	if err != nil {
-		return "", err
-	}
-
-	for _, tag := range tags {
-		if tag.Title == "to_read" {
-			return tag.ID, nil
-		}
-	}
+// runSync performs one full Pocket -> Joplin sync: fetch changed articles,
+// create/update/archive their Joplin notes, sync tags, and persist the new
+// high-water mark. It's used for both one-shot runs and each tick of the
+// daemon's schedule.
+func runSync(ctx context.Context, cfg config, showProgress bool) error {
+	pocket := newPocketClient(cfg.Pocket)
+	joplin := newJoplinClient(cfg.Joplin)
 
-	return createJoplinTag("to_read", config)
-}
-
-func fetchJoplinTags(config joplinConfig) ([]JoplinTag, error) {
-	resp, err := http.Get(config.BaseURL + "/tags?token=" + config.Token)
+	state, err := loadSyncState(cfg.State)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("failed to load sync state: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch tags, status code: %d", resp.StatusCode)
+	articles, newSince, err := pocket.fetchUnreadArticles(ctx, state.LastSync)
+	if err != nil {
+		return fmt.Errorf("failed to fetch articles from Pocket: %w", err)
 	}
 
-	var respStruct struct {
-		Tags []JoplinTag `json:"items"`
+	tagSync := newTagSyncer(joplin, cfg.TagSync.Prefix)
+	if err := tagSync.warm(ctx); err != nil {
+		return fmt.Errorf("failed to load existing Joplin tags: %w", err)
 	}
-	if err = json.NewDecoder(resp.Body).Decode(&respStruct); err != nil {
-		return nil, err
-	}
-
-	return respStruct.Tags, nil
-}
 
-func createJoplinTag(title string, config joplinConfig) (string, error) {
-	tag := JoplinTag{Title: title}
-	body, err := json.Marshal(tag)
-	if err != nil {
-		return "", err
+	var toReadTagID string
+	if cfg.TagSync.AddToReadTag {
+		toReadTagID, err = joplin.getOrCreateToReadTag(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get or create the 'to_read' tag in Joplin: %w", err)
+		}
 	}
 
-	resp, err := http.Post(config.BaseURL+"/tags?toke="+config.Token, "application/json", bytes.NewReader(body))
+	folderID, err := joplin.getOrCreateMainFolder(ctx)
 	if err != nil {
-		return "", err
+		return fmt.Errorf("failed to get or create the 'Main' folder in Joplin: %w", err)
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to create tag, status code: %d", resp.StatusCode)
+	var bar *pb.ProgressBar
+	if showProgress {
+		bar = pb.StartNew(len(articles))
+		defer bar.Finish()
 	}
 
-	var createdTag JoplinTag
-	if err = json.NewDecoder(resp.Body).Decode(&createdTag); err != nil {
-		return "", err
-	}
+	var archiveFolderID string
 
-	return createdTag.ID, nil
-}
-
-func getOrCreateMainFolder(config joplinConfig) (string, error) {
-	folders, err := fetchJoplinFolders(config)
-	if err != nil {
-		return "", err
+	failures := newSyncFailureTracker()
+	recordFailure := func(article PocketArticle) {
+		failures.record(article.TimeUpdated)
 	}
 
-	for _, folder := range folders {
-		if folder.Title == "Main" {
-			return folder.ID, nil
+	for _, article := range articles {
+		if bar != nil {
+			bar.Increment()
 		}
-	}
 
-	return createJoplinFolder("Main", config)
-}
+		existing, hasExisting := state.Items[article.ItemID]
 
-func fetchJoplinFolders(config joplinConfig) ([]JoplinFolder, error) {
-	resp, err := http.Get(config.BaseURL + "/folders?token=" + config.Token)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+		if article.Status == pocketStatusArchived || article.Status == pocketStatusDeleted {
+			if !hasExisting {
+				continue
+			}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch folders, status code: %d", resp.StatusCode)
-	}
+			if err := joplin.removeNoteForArticle(ctx, cfg.State.ArchiveBehavior, existing.NoteID, &archiveFolderID); err != nil {
+				fmt.Println("Error removing note from Joplin:", err)
+				recordFailure(article)
+				continue
+			}
 
-	var respStruct struct {
-		Folders []JoplinFolder `json:"items"`
-	}
-	if err = json.NewDecoder(resp.Body).Decode(&respStruct); err != nil {
-		return nil, err
-	}
+			delete(state.Items, article.ItemID)
+			continue
+		}
 
-	return respStruct.Folders, nil
-}
+		var extracted string
+		if cfg.Extraction.Enabled {
+			var err error
+			extracted, err = extractArticle(ctx, cfg.Extraction, joplin, article.URL)
+			if err != nil {
+				fmt.Println("Error extracting article content, falling back to the excerpt:", err)
+			}
+		}
+		body := noteBodyForArticle(article, extracted)
+
+		if hasExisting {
+			if err := joplin.updateNoteForArticle(ctx, existing.NoteID, article, body); err != nil {
+				fmt.Println("Error updating note in Joplin:", err)
+				recordFailure(article)
+				continue
+			}
+		} else {
+			noteID, err := joplin.createNoteForArticle(ctx, folderID, article, body)
+			if err != nil {
+				fmt.Println("Error creating note in Joplin:", err)
+				recordFailure(article)
+				continue
+			}
+			existing.NoteID = noteID
+		}
 
-func createJoplinFolder(title string, config joplinConfig) (string, error) {
-	folder := JoplinFolder{Title: title}
-	body, err := json.Marshal(folder)
-	if err != nil {
-		return "", err
-	}
+		// Record the note against the item right away, before tag sync, so
+		// a tag-sync failure below can't leave an untracked, orphaned note
+		// that would otherwise be recreated on a future run.
+		existing.TimeUpdated = parseUnixTimestamp(article.TimeUpdated)
+		state.Items[article.ItemID] = existing
 
-	resp, err := http.Post(config.BaseURL+"/folders?token="+config.Token, "application/json", bytes.NewReader(body))
-	if err != nil {
-		return "", err
+		tagIDs, err := tagSync.tagIDsFor(ctx, article)
+		if err != nil {
+			fmt.Println("Error syncing Pocket tags to Joplin:", err)
+			recordFailure(article)
+			continue
+		}
+		if toReadTagID != "" {
+			tagIDs = append(tagIDs, toReadTagID)
+		}
+		if err := tagSync.applyTags(ctx, existing.NoteID, tagIDs); err != nil {
+			fmt.Println("Error tagging note in Joplin:", err)
+			recordFailure(article)
+			continue
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to create folder, status code: %d", resp.StatusCode)
+	state.LastSync = failures.cap(newSince)
+	if err := saveSyncState(cfg.State, state); err != nil {
+		return fmt.Errorf("failed to save sync state: %w", err)
 	}
 
-	var createdFolder JoplinFolder
-	if err = json.NewDecoder(resp.Body).Decode(&createdFolder); err != nil {
-		return "", err
+	if !showProgress {
+		fmt.Println("All articles have been processed.")
 	}
 
-	return createdFolder.ID, nil
+	return nil
 }
 
-func createJoplinNoteForArticle(tagID, parentID string, config joplinConfig, article PocketArticle) error {
-	note := map[string]string{
-		"title":     article.Title,
-		"body":      article.URL,
-		"parent_id": parentID,
-	}
-	body, err := json.Marshal(note)
-	if err != nil {
-		return err
-	}
-
-	resp, err := http.Post(config.BaseURL+"/notes?token="+config.Token, "application/json", bytes.NewReader(body))
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to create note, status code: %d", resp.StatusCode)
-	}
-
-	tagNoteURL := fmt.Sprintf("%s/tags/%s/notes?token=%s", config.BaseURL, tagID, config.Token)
-	req, err := http.NewRequest(http.MethodPost, tagNoteURL, resp.Body)
+// parseUnixTimestamp converts a Pocket unix-timestamp string (e.g.
+// time_updated) into an int64, returning 0 if it can't be parsed.
+func parseUnixTimestamp(s string) int64 {
+	t, err := strconv.ParseInt(s, 10, 64)
 	if err != nil {
-		return err
+		return 0
 	}
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err = client.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to tag note, status code: %d", resp.StatusCode)
-	}
-
-	return nil
+	return t
 }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// pocketRateLimit approximates Pocket's documented ~320 requests/hour limit,
+// leaving some headroom for bursts from a single sync run.
+const pocketRateLimit = 300
+
+// Pocket item statuses, as returned in the "status" field of /v3/get.
+const (
+	pocketStatusUnread   = "0"
+	pocketStatusArchived = "1"
+	pocketStatusDeleted  = "2"
+)
+
+type PocketArticle struct {
+	ItemID      string               `json:"item_id"`
+	Title       string               `json:"resolved_title"`
+	URL         string               `json:"resolved_url"`
+	Excerpt     string               `json:"excerpt"`
+	TimeAdded   string               `json:"time_added"`
+	TimeUpdated string               `json:"time_updated"`
+	Status      string               `json:"status"`
+	Tags        map[string]PocketTag `json:"tags"`
+}
+
+// PocketTag is the metadata Pocket attaches to each tag name in an
+// article's "tags" object when detailType=complete is requested.
+type PocketTag struct {
+	Tag string `json:"tag"`
+}
+
+type PocketResponse struct {
+	List  map[string]PocketArticle `json:"list"`
+	Since int64                    `json:"since"`
+}
+
+// pocketClient wraps the Pocket v3 API behind a rate-limited, retrying
+// HTTP client.
+type pocketClient struct {
+	config pocketConfig
+	client *retryingClient
+}
+
+func newPocketClient(config pocketConfig) *pocketClient {
+	return &pocketClient{
+		config: config,
+		client: newRetryingClient(&http.Client{}, rate.NewLimiter(rate.Limit(pocketRateLimit)/3600, 5), 30*time.Second),
+	}
+}
+
+func (p *pocketClient) fetchUnreadArticles(ctx context.Context, since int64) ([]PocketArticle, int64, error) {
+	query := fmt.Sprintf("https://getpocket.com/v3/get?consumer_key=%s&access_token=%s&state=all&detailType=complete",
+		p.config.ConsumerKey,
+		p.config.AccessToken,
+	)
+	if since > 0 {
+		query += fmt.Sprintf("&since=%d", since)
+	}
+
+	resp, err := p.client.do(ctx, func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodGet, query, nil)
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("failed to fetch articles, status code: %d", resp.StatusCode)
+	}
+
+	var pocketResp PocketResponse
+	if err = json.NewDecoder(resp.Body).Decode(&pocketResp); err != nil {
+		return nil, 0, err
+	}
+
+	articles := make([]PocketArticle, 0, len(pocketResp.List))
+	for _, article := range pocketResp.List {
+		articles = append(articles, article)
+	}
+
+	return articles, pocketResp.Since, nil
+}
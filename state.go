@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+type stateConfig struct {
+	FilePath        string `env:"STATE_FILE_PATH"`
+	ArchiveBehavior string `env:"ARCHIVE_BEHAVIOR" envDefault:"delete"`
+}
+
+// itemState records what a Pocket item was last synced as, so subsequent
+// runs can tell whether it's new, changed, or already up to date in Joplin.
+type itemState struct {
+	NoteID      string `json:"note_id"`
+	TimeUpdated int64  `json:"time_updated"`
+}
+
+// syncState is persisted to stateConfig.FilePath between runs so imports
+// are idempotent and incremental.
+type syncState struct {
+	LastSync int64                `json:"last_sync"`
+	Items    map[string]itemState `json:"items"`
+}
+
+func loadSyncState(cfg stateConfig) (syncState, error) {
+	path, err := resolveStatePath(cfg)
+	if err != nil {
+		return syncState{}, err
+	}
+
+	state := syncState{Items: map[string]itemState{}}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return syncState{}, err
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return syncState{}, err
+	}
+	if state.Items == nil {
+		state.Items = map[string]itemState{}
+	}
+
+	return state, nil
+}
+
+func saveSyncState(cfg stateConfig, state syncState) error {
+	path, err := resolveStatePath(cfg)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o600)
+}
+
+// syncFailureTracker records the time_updated of articles that failed to
+// sync during a run, so the caller can cap how far LastSync advances: it
+// must never pass a failed article's time_updated, or Pocket's since=
+// filter would never return that item again for a retry.
+type syncFailureTracker struct {
+	since int64
+}
+
+func newSyncFailureTracker() *syncFailureTracker {
+	return &syncFailureTracker{since: -1}
+}
+
+// record notes that the article with this time_updated failed to sync.
+func (t *syncFailureTracker) record(timeUpdated string) {
+	ts := parseUnixTimestamp(timeUpdated)
+	if ts <= 0 {
+		return
+	}
+	if t.since == -1 || ts-1 < t.since {
+		t.since = ts - 1
+	}
+}
+
+// cap returns lastSync, or the earliest recorded failure's time_updated
+// minus one, whichever is smaller.
+func (t *syncFailureTracker) cap(lastSync int64) int64 {
+	if t.since >= 0 && t.since < lastSync {
+		return t.since
+	}
+	return lastSync
+}
+
+func resolveStatePath(cfg stateConfig) (string, error) {
+	if cfg.FilePath != "" {
+		return cfg.FilePath, nil
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "pocket-to-joplin", "state.json"), nil
+}
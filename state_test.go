@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestSyncFailureTrackerCapNoFailures(t *testing.T) {
+	tracker := newSyncFailureTracker()
+
+	if got := tracker.cap(100); got != 100 {
+		t.Errorf("cap(100) = %d, want 100", got)
+	}
+}
+
+func TestSyncFailureTrackerCapsToEarliestFailure(t *testing.T) {
+	tracker := newSyncFailureTracker()
+	tracker.record("50")
+	tracker.record("30")
+	tracker.record("40")
+
+	if got := tracker.cap(100); got != 29 {
+		t.Errorf("cap(100) = %d, want 29", got)
+	}
+}
+
+func TestSyncFailureTrackerIgnoresUnparsableTimestamps(t *testing.T) {
+	tracker := newSyncFailureTracker()
+	tracker.record("not-a-number")
+	tracker.record("")
+
+	if got := tracker.cap(100); got != 100 {
+		t.Errorf("cap(100) = %d, want 100", got)
+	}
+}
+
+func TestSyncFailureTrackerDoesNotRaiseLastSync(t *testing.T) {
+	tracker := newSyncFailureTracker()
+	tracker.record("500")
+
+	if got := tracker.cap(100); got != 100 {
+		t.Errorf("cap(100) = %d, want 100 (a later failure must not raise LastSync)", got)
+	}
+}
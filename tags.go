@@ -0,0 +1,82 @@
+package main
+
+import "context"
+
+type tagSyncConfig struct {
+	Prefix       string `env:"TAG_PREFIX"`
+	AddToReadTag bool   `env:"ADD_TO_READ_TAG" envDefault:"false"`
+}
+
+// tagSyncer maps Pocket tag names onto Joplin tag ids, creating any Joplin
+// tag that doesn't exist yet and caching the mapping for the rest of the
+// run so repeated tags only cost one lookup.
+type tagSyncer struct {
+	joplin *joplinClient
+	prefix string
+	cache  map[string]string
+}
+
+func newTagSyncer(joplin *joplinClient, prefix string) *tagSyncer {
+	return &tagSyncer{joplin: joplin, prefix: prefix, cache: map[string]string{}}
+}
+
+// warm populates the cache from Joplin's existing tags, so tags already
+// present aren't recreated.
+func (t *tagSyncer) warm(ctx context.Context) error {
+	tags, err := t.joplin.fetchTags(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		t.cache[tag.Title] = tag.ID
+	}
+
+	return nil
+}
+
+// tagIDsFor returns the Joplin tag ids for all of article's Pocket tags,
+// namespaced under the configured prefix and created in Joplin as needed.
+func (t *tagSyncer) tagIDsFor(ctx context.Context, article PocketArticle) ([]string, error) {
+	ids := make([]string, 0, len(article.Tags))
+	for name := range article.Tags {
+		id, err := t.tagID(ctx, t.namespacedTitle(name))
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func (t *tagSyncer) namespacedTitle(name string) string {
+	if t.prefix == "" {
+		return name
+	}
+	return t.prefix + "/" + name
+}
+
+func (t *tagSyncer) tagID(ctx context.Context, title string) (string, error) {
+	if id, ok := t.cache[title]; ok {
+		return id, nil
+	}
+
+	id, err := t.joplin.createTag(ctx, title)
+	if err != nil {
+		return "", err
+	}
+	t.cache[title] = id
+
+	return id, nil
+}
+
+// applyTags associates noteID with every tag in tagIDs.
+func (t *tagSyncer) applyTags(ctx context.Context, noteID string, tagIDs []string) error {
+	for _, tagID := range tagIDs {
+		if err := t.joplin.addNoteTag(ctx, tagID, noteID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
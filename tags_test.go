@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestTagSyncerNamespacedTitle(t *testing.T) {
+	cases := []struct {
+		prefix string
+		name   string
+		want   string
+	}{
+		{prefix: "", name: "golang", want: "golang"},
+		{prefix: "pocket", name: "golang", want: "pocket/golang"},
+	}
+
+	for _, c := range cases {
+		syncer := newTagSyncer(nil, c.prefix)
+		if got := syncer.namespacedTitle(c.name); got != c.want {
+			t.Errorf("namespacedTitle(%q) with prefix %q = %q, want %q", c.name, c.prefix, got, c.want)
+		}
+	}
+}
+
+func TestTagSyncerTagIDUsesCache(t *testing.T) {
+	syncer := newTagSyncer(nil, "")
+	syncer.cache["golang"] = "existing-id"
+
+	id, err := syncer.tagID(context.Background(), "golang")
+	if err != nil {
+		t.Fatalf("tagID returned an error for a cached title: %v", err)
+	}
+	if id != "existing-id" {
+		t.Errorf("tagID(%q) = %q, want %q", "golang", id, "existing-id")
+	}
+}